@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	flagCache  = flag.String("cache", "rw", "LLM response cache mode: off, read, write, or rw")
+	flagReplay = flag.Bool("replay", false, "regenerate entirely from the response cache, making no network calls")
+)
+
+// defaultTemperature is folded into the cache key; there's no --temperature flag yet.
+const defaultTemperature = 0.0
+
+func cacheDir() string {
+	return pathInTargetDir(filepath.Join(".smol-dev", "cache"))
+}
+
+func cacheKey(model, systemPrompt, humanPrompt string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%v", model, systemPrompt, humanPrompt, defaultTemperature)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type cacheEntry struct {
+	Content string `json:"content"`
+}
+
+func cachePath(key string) string {
+	return filepath.Join(cacheDir(), key+".json")
+}
+
+func cacheRead(key string) (string, bool) {
+	if *flagCache == "off" || *flagCache == "write" {
+		return "", false
+	}
+
+	b, err := os.ReadFile(cachePath(key))
+	if err != nil {
+		return "", false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return "", false
+	}
+	return entry.Content, true
+}
+
+func cacheWrite(key, content string) error {
+	if *flagCache == "off" || *flagCache == "read" {
+		return nil
+	}
+
+	if err := os.MkdirAll(cacheDir(), 0755); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cacheEntry{Content: content})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cachePath(key), b, 0644)
+}
+
+// cachePrune backs the "smol-dev-go cache prune" subcommand.
+func cachePrune() error {
+	dir := cacheDir()
+	if !fileExists(dir) {
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to prune cache at %v: %w", dir, err)
+	}
+	fmt.Printf("pruned cache at %v\n", dir)
+	return nil
+}
+
+// cachingProvider wraps a Provider with a content-addressed response cache.
+// With --replay, a cache miss is an error rather than falling through to the network.
+type cachingProvider struct {
+	provider Provider
+}
+
+func withCache(p Provider) Provider {
+	return &cachingProvider{provider: p}
+}
+
+func (c *cachingProvider) GenerateJSON(ctx context.Context, model, systemPrompt, humanPrompt string) (string, error) {
+	key := cacheKey(model, systemPrompt, humanPrompt)
+	if content, ok := cacheRead(key); ok {
+		return content, nil
+	}
+	if *flagReplay {
+		return "", fmt.Errorf("--replay: no cached response for key %v", key)
+	}
+
+	content, err := c.provider.GenerateJSON(ctx, model, systemPrompt, humanPrompt)
+	if err != nil {
+		return "", err
+	}
+	if err := cacheWrite(key, content); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write cache entry: %v\n", err)
+	}
+	return content, nil
+}
+
+func (c *cachingProvider) GenerateStream(ctx context.Context, model, systemPrompt, humanPrompt string, onChunk func(ctx context.Context, chunk []byte) error) error {
+	key := cacheKey(model, systemPrompt, humanPrompt)
+	if content, ok := cacheRead(key); ok {
+		return onChunk(ctx, []byte(content))
+	}
+	if *flagReplay {
+		return fmt.Errorf("--replay: no cached response for key %v", key)
+	}
+
+	var buf bytes.Buffer
+	err := c.provider.GenerateStream(ctx, model, systemPrompt, humanPrompt, func(ctx context.Context, chunk []byte) error {
+		buf.Write(chunk)
+		return onChunk(ctx, chunk)
+	})
+	if err != nil {
+		return err
+	}
+	if err := cacheWrite(key, buf.String()); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write cache entry: %v\n", err)
+	}
+	return nil
+}
+
+func (c *cachingProvider) GenerateToolCall(ctx context.Context, model, systemPrompt, humanPrompt string, tool ToolSpec) (json.RawMessage, bool, error) {
+	caller, ok := c.provider.(ToolCaller)
+	if !ok {
+		return nil, false, nil
+	}
+
+	key := cacheKey(model, systemPrompt, humanPrompt+"\x00tool:"+tool.Name)
+	if content, ok := cacheRead(key); ok {
+		return json.RawMessage(content), true, nil
+	}
+	if *flagReplay {
+		return nil, false, fmt.Errorf("--replay: no cached response for key %v", key)
+	}
+
+	args, used, err := caller.GenerateToolCall(ctx, model, systemPrompt, humanPrompt, tool)
+	if err != nil || !used {
+		return args, used, err
+	}
+
+	if err := cacheWrite(key, string(args)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write cache entry: %v\n", err)
+	}
+	return args, true, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	flagEvents       = flag.String("events", "", "event output mode: \"jsonl\" writes structured events to stdout instead of the mpb progress bars")
+	flagEventsSocket = flag.String("events-socket", "", "unix socket path to serve structured events over, for editor/IDE integration")
+)
+
+type EventKind string
+
+const (
+	EventKindStart      EventKind = "start"
+	EventKindToken      EventKind = "token"
+	EventKindComplete   EventKind = "complete"
+	EventKindError      EventKind = "error"
+	EventKindDiagnostic EventKind = "diagnostic"
+)
+
+// Event is a single structured record published by a generation stage.
+type Event struct {
+	Ts      time.Time   `json:"ts"`
+	Stage   string      `json:"stage"`
+	File    string      `json:"file,omitempty"`
+	Kind    EventKind   `json:"kind"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+type eventSink interface {
+	publish(Event)
+}
+
+type eventBus struct {
+	sinks []eventSink
+}
+
+var events = &eventBus{}
+
+// initEvents wires up the event sinks selected by --events and
+// --events-socket. Must be called once, after flag.Parse.
+func initEvents() error {
+	switch *flagEvents {
+	case "":
+	case "jsonl":
+		events.sinks = append(events.sinks, &jsonlSink{w: os.Stdout})
+	default:
+		return fmt.Errorf("unknown --events mode %q (want \"jsonl\")", *flagEvents)
+	}
+
+	if *flagEventsSocket != "" {
+		sink, err := newSocketSink(*flagEventsSocket)
+		if err != nil {
+			return fmt.Errorf("failed to start events socket: %w", err)
+		}
+		events.sinks = append(events.sinks, sink)
+	}
+
+	return nil
+}
+
+func usingJSONLEvents() bool {
+	return *flagEvents == "jsonl"
+}
+
+func emit(stage, file string, kind EventKind, payload interface{}) {
+	if len(events.sinks) == 0 {
+		return
+	}
+	e := Event{Ts: time.Now(), Stage: stage, File: file, Kind: kind, Payload: payload}
+	for _, sink := range events.sinks {
+		sink.publish(e)
+	}
+}
+
+type jsonlSink struct {
+	mu sync.Mutex
+	w  *os.File
+}
+
+func (s *jsonlSink) publish(e Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(b)
+}
+
+// socketSink serves every published event to clients of a unix socket.
+type socketSink struct {
+	mu      sync.Mutex
+	clients map[net.Conn]*bufio.Writer
+}
+
+func newSocketSink(path string) (*socketSink, error) {
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &socketSink{clients: map[net.Conn]*bufio.Writer{}}
+	go s.acceptLoop(l)
+	return s, nil
+}
+
+func (s *socketSink) acceptLoop(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.clients[conn] = bufio.NewWriter(conn)
+		s.mu.Unlock()
+	}
+}
+
+func (s *socketSink) publish(e Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn, w := range s.clients {
+		if _, err := w.Write(b); err != nil || w.Flush() != nil {
+			conn.Close()
+			delete(s.clients, conn)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"golang.org/x/tools/imports"
+)
+
+var externalFormatters = map[string][]string{
+	".js":   {"prettier", "--write"},
+	".jsx":  {"prettier", "--write"},
+	".ts":   {"prettier", "--write"},
+	".tsx":  {"prettier", "--write"},
+	".json": {"prettier", "--write"},
+	".css":  {"prettier", "--write"},
+	".html": {"prettier", "--write"},
+	".md":   {"prettier", "--write"},
+	".py":   {"black", "-q"},
+	".rs":   {"rustfmt"},
+}
+
+// formatGeneratedFile formats fp in place; a nil error also means "no
+// formatter configured/available" for its extension.
+func formatGeneratedFile(fp string) error {
+	if filepath.Ext(fp) == ".go" {
+		return formatGoFile(fp)
+	}
+
+	cmd, ok := externalFormatters[filepath.Ext(fp)]
+	if !ok {
+		return nil
+	}
+	if _, err := exec.LookPath(cmd[0]); err != nil {
+		return nil
+	}
+
+	args := append(append([]string{}, cmd[1:]...), fp)
+	if out, err := exec.Command(cmd[0], args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+func formatGoFile(fp string) error {
+	src, err := os.ReadFile(fp)
+	if err != nil {
+		return fmt.Errorf("failed to read %v for formatting: %w", fp, err)
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("gofmt failed: %w", err)
+	}
+
+	withImports, err := imports.Process(fp, formatted, nil)
+	if err != nil {
+		return fmt.Errorf("goimports failed: %w", err)
+	}
+
+	return os.WriteFile(fp, withImports, 0644)
+}
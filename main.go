@@ -9,13 +9,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 
-	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/openai"
 	"github.com/tmc/langchaingo/prompts"
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
@@ -30,29 +29,59 @@ var (
 	flagDebug           = flag.Bool("debug", false, "debug output (show prompts)")
 	flagFilesToGenerate = flag.String("files-to-generate", "", "file path to a yaml file containing a list of files to generate")
 	flagSharedDeps      = flag.String("shared-deps", "", "file path to a yaml file containing a list of shared dependencies")
+	flagNoFormat        = flag.Bool("no-format", false, "disable post-generation formatting/linting of generated files")
+	flagMaxRepairRounds = flag.Int("max-repair-rounds", 0, "number of build-and-repair rounds to attempt after generation (0 disables the build/repair loop)")
+	flagBuildTimeout    = flag.Duration("build-timeout", 2*time.Minute, "timeout for each build verification step in the repair loop")
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		if err := runCacheCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+func runCacheCommand(args []string) error {
+	if len(args) == 0 || args[0] != "prune" {
+		return fmt.Errorf("usage: %v cache prune [-target-dir dir]", os.Args[0])
+	}
+	if err := flag.CommandLine.Parse(args[1:]); err != nil {
+		return err
+	}
+	return cachePrune()
+}
+
 func run() error {
 	flag.Parse()
 
+	if err := initEvents(); err != nil {
+		return err
+	}
+
+	provider, err := newProvider()
+	if err != nil {
+		return fmt.Errorf("failed to set up LLM provider: %w", err)
+	}
+
 	prompt, err := readPrompt()
 	if err != nil {
 		return err
 	}
 
-	filesToGenerate, err := getFilesToGenerate(prompt, *flagFilesToGenerate)
+	filesToGenerate, err := getFilesToGenerate(provider, prompt, *flagFilesToGenerate)
 	if err != nil {
 		return fmt.Errorf("failed to get files to generate: %w", err)
 	}
 
-	sharedDeps, err := getSharedDependencies(prompt, filesToGenerate, *flagSharedDeps)
+	sharedDeps, err := getSharedDependencies(provider, prompt, filesToGenerate, *flagSharedDeps)
 	if err != nil {
 		return fmt.Errorf("failed to get shared dependencies: %w", err)
 	}
@@ -62,57 +91,120 @@ func run() error {
 		return fmt.Errorf("failed to marshal shared dependencies: %w", err)
 	}
 
-	return generateFiles(prompt, filesToGenerate, string(sharedDepsYaml))
+	if err := generateFiles(provider, prompt, filesToGenerate, string(sharedDepsYaml)); err != nil {
+		return err
+	}
+
+	return runBuildRepairLoop(provider, prompt, filesToGenerate, string(sharedDepsYaml))
 }
 
-func generateFiles(prompt string, filesToGenerate []string, sharedDepsYaml string) error {
+func generateFiles(provider Provider, prompt string, filesToGenerate []string, sharedDepsYaml string) error {
 	g := new(errgroup.Group)
 	g.SetLimit(*flagConcurrency)
 
-	progressBars := mpb.New()
+	manifest, err := loadRunManifest()
+	if err != nil {
+		return fmt.Errorf("failed to load run manifest: %w", err)
+	}
+
+	var progressBars *mpb.Progress
+	if !usingJSONLEvents() {
+		progressBars = mpb.New()
+	}
 	for i, fp := range filesToGenerate {
 		fp := pathInTargetDir(fp)
 
-		if fileExists(fp) {
-			fmt.Printf("file %v already exists, skipping\n", fp)
+		if fileExists(fp) && manifest.isCompleted(fp) {
+			if !usingJSONLEvents() {
+				fmt.Printf("file %v already exists, skipping\n", fp)
+			}
 			continue
 		}
 
 		g.Go(func() error {
-			return generateFile(prompt, fp, sharedDepsYaml, filesToGenerate, i, len(filesToGenerate), progressBars)
+			if err := generateFile(provider, prompt, fp, sharedDepsYaml, filesToGenerate, i, len(filesToGenerate), progressBars); err != nil {
+				return err
+			}
+			return manifest.markCompleted(fp)
 		})
 		time.Sleep(time.Millisecond)
 	}
 
-	err := g.Wait()
-	progressBars.Wait()
+	err = g.Wait()
+	if progressBars != nil {
+		progressBars.Wait()
+	}
 	return err
 }
 
-func generateFile(prompt, fp, sharedDepsYaml string, filesToGenerate []string, i, total int, progressBars *mpb.Progress) error {
+func generateFile(provider Provider, prompt, fp, sharedDepsYaml string, filesToGenerate []string, i, total int, progressBars *mpb.Progress) error {
 	msg := fmt.Sprintf("generating file %v of %v: %v", i+1, total, fp)
-	bar := progressBars.AddBar(1, mpb.PrependDecorators(
-		decor.Name(msg),
-	), mpb.AppendDecorators(
-		decor.OnComplete(decor.Spinner(nil), "✅"),
-	), mpb.BarNoPop())
+	emit("codegen", fp, EventKindStart, msg)
 
-	defer bar.SetCurrent(1)
-	fmt.Println(msg)
+	var status *barStatus
+	if progressBars != nil {
+		status = new(barStatus)
+		bar := progressBars.AddBar(1, mpb.PrependDecorators(
+			decor.Name(msg),
+		), mpb.AppendDecorators(
+			decor.OnComplete(decor.Spinner(nil), "✅"),
+			decor.Any(status.render),
+		), mpb.BarNoPop())
+
+		defer bar.SetCurrent(1)
+		fmt.Println(msg)
+	}
 
 	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		emit("codegen", fp, EventKindError, err.Error())
 		return fmt.Errorf("failed to create directory %v: %w", filepath.Dir(fp), err)
 	}
 
-	return runCodeGenLLMCall(prompt, msg, fp, sharedDepsYaml, filesToGenerate)
+	if err := runCodeGenLLMCall(provider, prompt, msg, fp, sharedDepsYaml, filesToGenerate); err != nil {
+		emit("codegen", fp, EventKindError, err.Error())
+		return err
+	}
+
+	if !*flagNoFormat {
+		if err := formatGeneratedFile(fp); err != nil {
+			if status != nil {
+				status.set(fmt.Sprintf(" (format failed: %v)", err))
+			}
+			fmt.Fprintf(os.Stderr, "warning: failed to format %v: %v\n", fp, err)
+			emit("format", fp, EventKindError, err.Error())
+		}
+	}
+
+	emit("codegen", fp, EventKindComplete, nil)
+	return nil
+}
+
+// barStatus is a small mutable label rendered alongside a file's progress
+// bar, used to surface non-fatal warnings (like a failed formatting pass)
+// without aborting the run.
+type barStatus struct {
+	mu  sync.Mutex
+	msg string
+}
+
+func (s *barStatus) set(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msg = msg
+}
+
+func (s *barStatus) render(decor.Statistics) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.msg
 }
 
-func getFilesToGenerate(prompt, flagFilesToGenerate string) ([]string, error) {
+func getFilesToGenerate(provider Provider, prompt, flagFilesToGenerate string) ([]string, error) {
 	if flagFilesToGenerate != "" && fileExistsAndNonEmpty(flagFilesToGenerate) {
 		return readStringSliceFromYaml(flagFilesToGenerate)
 	}
 
-	filePathsResult, err := runFilePathsLLMCall(prompt)
+	filePathsResult, err := runFilePathsLLMCall(provider, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run file paths LLM call: %w", err)
 	}
@@ -141,12 +233,12 @@ type sharedDependency struct {
 	Symbols     map[string]string `json:"symbols"`
 }
 
-func getSharedDependencies(prompt string, filesToGenerate []string, flagSharedDeps string) (*SharedDependenciesLLMResponse, error) {
+func getSharedDependencies(provider Provider, prompt string, filesToGenerate []string, flagSharedDeps string) (*SharedDependenciesLLMResponse, error) {
 	if flagSharedDeps != "" && fileExistsAndNonEmpty(flagSharedDeps) {
 		return readSharedDependenciesFromYaml(flagSharedDeps)
 	}
 
-	sharedDepsResult, err := runSharedDependenciesLLMCall(prompt, filesToGenerate)
+	sharedDepsResult, err := runSharedDependenciesLLMCall(provider, prompt, filesToGenerate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run shared dependencies LLM call: %w", err)
 	}
@@ -181,61 +273,101 @@ type filepathLLMResponse struct {
 	Reasoning []string `json:"reasoning"`
 }
 
-func runFilePathsLLMCall(prompt string) (*filepathLLMResponse, error) {
+func runFilePathsLLMCall(provider Provider, prompt string) (*filepathLLMResponse, error) {
+	emit("files", "", EventKindStart, nil)
+
 	if *flagVerbose {
 		fmt.Println("running file paths LLM call")
 	} else {
 		defer spin("generating file list", "finished generating file list")()
 	}
 
-	ctx := context.Background()
-	llm, err := openai.New(openai.WithModel(*flagModel))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create llm: %w", err)
-	}
-
 	if *flagDebug {
 		fmt.Println("debug mode enabled, dumping prompt")
 		fmt.Println(filesPathsPrompt)
 		fmt.Println(prompt)
 	}
 
-	cr, err := llm.GenerateContent(ctx, []llms.MessageContent{
-		llms.TextParts(llms.ChatMessageTypeSystem, prompt),
-		llms.TextParts(llms.ChatMessageTypeHuman, filesPathsPrompt),
-	}, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-		fmt.Fprint(os.Stderr, string(chunk))
-		return nil
-	}))
+	ctx := context.Background()
+
+	if caller, ok := provider.(ToolCaller); ok {
+		args, used, err := caller.GenerateToolCall(ctx, planningModel(), prompt, filesPathsPrompt, recordFilepathsTool)
+		if err != nil {
+			if !IsUnsupportedToolCallError(err) {
+				emit("files", "", EventKindError, err.Error())
+				return nil, fmt.Errorf("failed to call %v tool: %w", recordFilepathsTool.Name, err)
+			}
+			// Some providers (e.g. Ollama/LocalAI backends) reject an
+			// unrecognized tools field outright rather than ignoring it, so
+			// treat this as "tool calling unsupported" and fall back to the
+			// regex JSON path below.
+			emit("files", "", EventKindDiagnostic, fmt.Sprintf("tool call unsupported, falling back to regex JSON: %v", err))
+			used = false
+		}
+		if used {
+			result := &filepathLLMResponse{}
+			if err := json.Unmarshal(args, result); err != nil {
+				emit("files", "", EventKindError, err.Error())
+				return nil, fmt.Errorf("failed to unmarshal %v arguments: %w\nRaw output: %s", recordFilepathsTool.Name, err, args)
+			}
+			emit("files", "", EventKindComplete, result.Filepaths)
+			return result, nil
+		}
+	}
 
+	content, err := provider.GenerateJSON(ctx, planningModel(), prompt, filesPathsPrompt)
 	if err != nil {
+		emit("files", "", EventKindError, err.Error())
 		return nil, fmt.Errorf("failed to chat: %w", err)
 	}
 
 	result := &filepathLLMResponse{}
-	if err = json.Unmarshal(findJSON(cr.Choices[0].Content), result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w\nRaw output: %v", err, cr.Choices[0].Content)
+	if err = json.Unmarshal(findJSON(content), result); err != nil {
+		emit("files", "", EventKindError, err.Error())
+		return nil, fmt.Errorf("failed to unmarshal response: %w\nRaw output: %v", err, content)
 	}
 
+	emit("files", "", EventKindComplete, result.Filepaths)
 	return result, nil
 }
 
-func runSharedDependenciesLLMCall(prompt string, filePaths []string) (*SharedDependenciesLLMResponse, error) {
+// recordFilepathsTool declares the function-calling tool used in place of
+// regex-scraped JSON for the file-list planning stage, for providers that
+// support tool calls.
+var recordFilepathsTool = ToolSpec{
+	Name:        "record_filepaths",
+	Description: "Record the complete, exhaustive list of filepaths the user would write to build the program.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"filepaths": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "The filepaths that the user would write to make the program.",
+			},
+			"reasoning": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "5-10 strings explaining the chain of thought behind the chosen filepaths.",
+			},
+		},
+		"required": []string{"filepaths", "reasoning"},
+	},
+}
+
+func runSharedDependenciesLLMCall(provider Provider, prompt string, filePaths []string) (*SharedDependenciesLLMResponse, error) {
+	emit("shared-deps", "", EventKindStart, nil)
+
 	if *flagVerbose {
 		fmt.Println("running file paths LLM call")
 	} else {
 		defer spin("generate dependencies list", "finished generating")()
 	}
 
-	ctx := context.Background()
 	pt := prompts.NewPromptTemplate(sharedDependenciesPrompt, []string{
 		"prompt", "filepaths_string",
 		"target_json",
 	})
-	llm, err := openai.New(openai.WithModel(*flagModel))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create llm: %w", err)
-	}
 
 	inputs := map[string]interface{}{
 		"prompt":           prompt,
@@ -256,36 +388,87 @@ func runSharedDependenciesLLMCall(prompt string, filePaths []string) (*SharedDep
 		return nil, fmt.Errorf("failed to format prompt: %w", err)
 	}
 
-	parts := []llms.MessageContent{
-		llms.TextParts(llms.ChatMessageTypeSystem, systemPrompt),
-		llms.TextParts(llms.ChatMessageTypeHuman, sharedDependenciesPrompt),
-	}
+	ctx := context.Background()
 
-	generation, err := llm.GenerateContent(ctx, parts, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-		fmt.Fprint(os.Stderr, string(chunk))
-		return nil
-	}))
+	if caller, ok := provider.(ToolCaller); ok {
+		args, used, err := caller.GenerateToolCall(ctx, planningModel(), systemPrompt, sharedDependenciesPrompt, recordSharedDependenciesTool)
+		if err != nil {
+			if !IsUnsupportedToolCallError(err) {
+				emit("shared-deps", "", EventKindError, err.Error())
+				return nil, fmt.Errorf("failed to call %v tool: %w", recordSharedDependenciesTool.Name, err)
+			}
+			// Some providers (e.g. Ollama/LocalAI backends) reject an
+			// unrecognized tools field outright rather than ignoring it, so
+			// treat this as "tool calling unsupported" and fall back to the
+			// regex JSON path below.
+			emit("shared-deps", "", EventKindDiagnostic, fmt.Sprintf("tool call unsupported, falling back to regex JSON: %v", err))
+			used = false
+		}
+		if used {
+			result := &SharedDependenciesLLMResponse{}
+			if err := json.Unmarshal(args, result); err != nil {
+				emit("shared-deps", "", EventKindError, err.Error())
+				return nil, fmt.Errorf("failed to unmarshal %v arguments: %w\nRaw output: %s", recordSharedDependenciesTool.Name, err, args)
+			}
+			emit("shared-deps", "", EventKindComplete, result.SharedDependencies)
+			return result, nil
+		}
+	}
 
+	content, err := provider.GenerateJSON(ctx, planningModel(), systemPrompt, sharedDependenciesPrompt)
 	if err != nil {
+		emit("shared-deps", "", EventKindError, err.Error())
 		return nil, fmt.Errorf("failed to get llm result: %w", err)
 	}
 
 	result := &SharedDependenciesLLMResponse{}
-	if err = json.Unmarshal(findJSON(generation.Choices[0].Content), result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w\nRaw output: %v", err, generation.Choices[0].Content)
+	if err = json.Unmarshal(findJSON(content), result); err != nil {
+		emit("shared-deps", "", EventKindError, err.Error())
+		return nil, fmt.Errorf("failed to unmarshal response: %w\nRaw output: %v", err, content)
 	}
 
+	emit("shared-deps", "", EventKindComplete, result.SharedDependencies)
 	return result, nil
 }
 
-func runCodeGenLLMCall(prompt, msg, file, sharedDeps string, filePaths []string) error {
-	ctx := context.Background()
+// recordSharedDependenciesTool declares the function-calling tool used in
+// place of regex-scraped JSON for the shared-dependencies planning stage,
+// for providers that support tool calls.
+var recordSharedDependenciesTool = ToolSpec{
+	Name:        "record_shared_dependencies",
+	Description: "Record what is shared between the files being generated: exported symbols, data schemas, DOM element ids, message names, and function names.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"shared_dependencies": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name":        map[string]any{"type": "string"},
+						"description": map[string]any{"type": "string"},
+						"symbols": map[string]any{
+							"type":                 "object",
+							"additionalProperties": map[string]any{"type": "string"},
+							"description":          "map of symbol name to symbol description",
+						},
+					},
+					"required": []string{"name", "description", "symbols"},
+				},
+			},
+			"reasoning": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "5-10 strings explaining the chain of thought behind the chosen shared dependencies.",
+			},
+		},
+		"required": []string{"shared_dependencies", "reasoning"},
+	},
+}
+
+func runCodeGenLLMCall(provider Provider, prompt, msg, file, sharedDeps string, filePaths []string) error {
 	spt := prompts.NewPromptTemplate(codeGenerationSystemPrompt, []string{"prompt", "filepaths_string", "shared_dependencies"})
 	pt := prompts.NewPromptTemplate(codeGenerationPrompt, []string{"prompt", "filepaths_string", "shared_dependencies", "filename"})
-	llm, err := openai.New(openai.WithModel(*flagModel))
-	if err != nil {
-		return fmt.Errorf("failed to create llm: %w", err)
-	}
 
 	inputs := map[string]interface{}{
 		"prompt":              prompt,
@@ -310,17 +493,13 @@ func runCodeGenLLMCall(prompt, msg, file, sharedDeps string, filePaths []string)
 	}
 	defer f.Close()
 
-	_, err = llm.GenerateContent(ctx, []llms.MessageContent{
-		llms.TextParts(llms.ChatMessageTypeSystem, systemPrompt),
-		llms.TextParts(llms.ChatMessageTypeHuman, genPrompt),
-	}, llms.WithModel(*flagModel), llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+	return provider.GenerateStream(context.Background(), codegenModel(), systemPrompt, genPrompt, func(ctx context.Context, chunk []byte) error {
+		emit("codegen", file, EventKindToken, string(chunk))
 		if _, err := f.Write(chunk); err != nil {
 			return fmt.Errorf("failed to write to file %v: %w", file, err)
 		}
 		return f.Sync()
-	}))
-
-	return err
+	})
 }
 
 func pathInTargetDir(path string) string {
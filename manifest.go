@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runManifest records which files a run has already generated successfully,
+// so a re-invocation (after a crash or a deliberate re-run) can skip them
+// without re-paying for the LLM calls. It complements the existing
+// fileExists skip, which only looks at whether the file is present on disk.
+type runManifest struct {
+	mu        sync.Mutex `yaml:"-"`
+	Completed []string   `yaml:"completed"`
+}
+
+func runManifestPath() string {
+	return pathInTargetDir(filepath.Join(".smol-dev", "run.yaml"))
+}
+
+func loadRunManifest() (*runManifest, error) {
+	m := &runManifest{}
+
+	b, err := os.ReadFile(runManifestPath())
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, yaml.Unmarshal(b, m)
+}
+
+func (m *runManifest) isCompleted(fp string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.Completed {
+		if c == fp {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *runManifest) markCompleted(fp string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Completed = append(m.Completed, fp)
+
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(runManifestPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(runManifestPath(), b, 0644)
+}
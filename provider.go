@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+var (
+	flagProvider      = flag.String("provider", "openai", "LLM provider to use: openai, anthropic, or openai-compatible (Ollama, LocalAI, vLLM, ...)")
+	flagBaseURL       = flag.String("base-url", "", "base URL override for the selected provider, required for --provider=openai-compatible")
+	flagAPIKeyEnv     = flag.String("api-key-env", "", "environment variable to read the provider API key from (defaults to the provider's own convention)")
+	flagPlanningModel = flag.String("planning-model", "", "model to use for the file-list and shared-dependencies planning stages (defaults to -model)")
+	flagCodegenModel  = flag.String("codegen-model", "", "model to use for per-file code generation and repair (defaults to -model)")
+)
+
+// Provider is the minimal surface smol-dev-go needs from an LLM backend.
+type Provider interface {
+	GenerateJSON(ctx context.Context, model, systemPrompt, humanPrompt string) (string, error)
+	GenerateStream(ctx context.Context, model, systemPrompt, humanPrompt string, onChunk func(ctx context.Context, chunk []byte) error) error
+}
+
+// ToolSpec describes a single function-calling tool exposed to the model.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  any // JSON schema
+}
+
+// ToolCaller is implemented by providers that support tool/function calling.
+// ok is false (with a nil error) if the provider didn't use the tool.
+type ToolCaller interface {
+	GenerateToolCall(ctx context.Context, model, systemPrompt, humanPrompt string, tool ToolSpec) (args json.RawMessage, ok bool, err error)
+}
+
+// newProvider builds the Provider selected by --provider, --base-url and --api-key-env.
+func newProvider() (Provider, error) {
+	var p Provider
+	switch *flagProvider {
+	case "openai":
+		opts := []openai.Option{}
+		if *flagBaseURL != "" {
+			opts = append(opts, openai.WithBaseURL(*flagBaseURL))
+		}
+		if *flagAPIKeyEnv != "" {
+			opts = append(opts, openai.WithToken(os.Getenv(*flagAPIKeyEnv)))
+		}
+		llm, err := openai.New(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create openai provider: %w", err)
+		}
+		p = &langchainProvider{llm: llm}
+
+	case "anthropic":
+		opts := []anthropic.Option{}
+		if *flagBaseURL != "" {
+			opts = append(opts, anthropic.WithBaseURL(*flagBaseURL))
+		}
+		if *flagAPIKeyEnv != "" {
+			opts = append(opts, anthropic.WithToken(os.Getenv(*flagAPIKeyEnv)))
+		}
+		llm, err := anthropic.New(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create anthropic provider: %w", err)
+		}
+		p = &langchainProvider{llm: llm}
+
+	case "openai-compatible":
+		if *flagBaseURL == "" {
+			return nil, fmt.Errorf("--base-url is required for --provider=openai-compatible")
+		}
+		token := "not-needed"
+		if *flagAPIKeyEnv != "" {
+			token = os.Getenv(*flagAPIKeyEnv)
+		}
+		llm, err := openai.New(openai.WithBaseURL(*flagBaseURL), openai.WithToken(token))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create openai-compatible provider: %w", err)
+		}
+		p = &langchainProvider{llm: llm}
+
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want openai, anthropic, or openai-compatible)", *flagProvider)
+	}
+
+	return withCache(p), nil
+}
+
+func planningModel() string {
+	if *flagPlanningModel != "" {
+		return *flagPlanningModel
+	}
+	return *flagModel
+}
+
+func codegenModel() string {
+	if *flagCodegenModel != "" {
+		return *flagCodegenModel
+	}
+	return *flagModel
+}
+
+type langchainProvider struct {
+	llm llms.Model
+}
+
+func (p *langchainProvider) generate(ctx context.Context, model, systemPrompt, humanPrompt string, streamingFunc func(ctx context.Context, chunk []byte) error, opts ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts = append([]llms.CallOption{llms.WithModel(model)}, opts...)
+	if streamingFunc != nil {
+		opts = append(opts, llms.WithStreamingFunc(streamingFunc))
+	}
+	return p.llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, systemPrompt),
+		llms.TextParts(llms.ChatMessageTypeHuman, humanPrompt),
+	}, opts...)
+}
+
+func (p *langchainProvider) GenerateJSON(ctx context.Context, model, systemPrompt, humanPrompt string) (string, error) {
+	var buf bytes.Buffer
+	cr, err := p.generate(ctx, model, systemPrompt, humanPrompt, func(ctx context.Context, chunk []byte) error {
+		buf.Write(chunk)
+		fmt.Fprint(os.Stderr, string(chunk))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(cr.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned")
+	}
+	return cr.Choices[0].Content, nil
+}
+
+func (p *langchainProvider) GenerateStream(ctx context.Context, model, systemPrompt, humanPrompt string, onChunk func(ctx context.Context, chunk []byte) error) error {
+	_, err := p.generate(ctx, model, systemPrompt, humanPrompt, onChunk)
+	return err
+}
+
+func (p *langchainProvider) GenerateToolCall(ctx context.Context, model, systemPrompt, humanPrompt string, tool ToolSpec) (json.RawMessage, bool, error) {
+	llmsTool := llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		},
+	}
+
+	cr, err := p.generate(ctx, model, systemPrompt, humanPrompt, nil,
+		llms.WithTools([]llms.Tool{llmsTool}),
+		llms.WithToolChoice(llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: tool.Name}}),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(cr.Choices) == 0 || len(cr.Choices[0].ToolCalls) == 0 {
+		return nil, false, nil
+	}
+
+	call := cr.Choices[0].ToolCalls[0]
+	if call.FunctionCall == nil {
+		return nil, false, nil
+	}
+	return json.RawMessage(call.FunctionCall.Arguments), true, nil
+}
+
+// IsUnsupportedToolCallError reports whether err looks like a provider
+// rejecting the tools fields outright, rather than a transient failure.
+func IsUnsupportedToolCallError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "400") {
+		return false
+	}
+	for _, kw := range []string{"tool", "function", "unrecognized", "unsupported", "unknown parameter"} {
+		if strings.Contains(msg, kw) {
+			return true
+		}
+	}
+	return false
+}
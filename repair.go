@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/tmc/langchaingo/prompts"
+)
+
+// diagnostic is a single compiler/build error parsed from a failed build,
+// attributed to the file and line it came from.
+type diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// runBuildRepairLoop compiles/tests the generated project and, on failure,
+// feeds the diagnostics back to the LLM to repair the offending files. It is
+// a no-op unless --max-repair-rounds is set and a supported toolchain is
+// detected in the target directory.
+func runBuildRepairLoop(provider Provider, prompt string, filesToGenerate []string, sharedDepsYaml string) error {
+	if *flagMaxRepairRounds <= 0 {
+		return nil
+	}
+
+	dir := *flagTargetDir
+	if dir == "" {
+		dir = "."
+	}
+
+	buildCmd, ok := detectBuildCommand(dir)
+	if !ok {
+		if !usingJSONLEvents() {
+			fmt.Println("repair loop: no supported toolchain detected, skipping")
+		}
+		return nil
+	}
+
+	for round := 1; round <= *flagMaxRepairRounds; round++ {
+		output, err := runBuildCommand(dir, buildCmd)
+		if err == nil {
+			if !usingJSONLEvents() {
+				fmt.Println("repair loop: build succeeded")
+			}
+			return nil
+		}
+
+		diagnostics := parseDiagnostics(output)
+		if len(diagnostics) == 0 {
+			return fmt.Errorf("build failed and no diagnostics could be parsed: %w\n%s", err, output)
+		}
+
+		if !usingJSONLEvents() {
+			fmt.Printf("repair round %v/%v: %v issue(s) found, attempting repair\n", round, *flagMaxRepairRounds, len(diagnostics))
+		}
+
+		for file, fileDiagnostics := range groupDiagnosticsByFile(diagnostics) {
+			fp := pathInTargetDir(file)
+			emit("repair", fp, EventKindDiagnostic, fileDiagnostics)
+
+			if err := archiveFileBeforeRepair(round, file, fp); err != nil {
+				return fmt.Errorf("failed to archive %v before repair: %w", fp, err)
+			}
+			if err := repairFile(provider, prompt, fp, sharedDepsYaml, filesToGenerate, fileDiagnostics); err != nil {
+				emit("repair", fp, EventKindError, err.Error())
+				return fmt.Errorf("failed to repair %v: %w", fp, err)
+			}
+			emit("repair", fp, EventKindComplete, nil)
+		}
+	}
+
+	if _, err := runBuildCommand(dir, buildCmd); err == nil {
+		if !usingJSONLEvents() {
+			fmt.Println("repair loop: build succeeded")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("build still failing after %v repair round(s)", *flagMaxRepairRounds)
+}
+
+// detectBuildCommand inspects dir for a recognized project manifest and
+// returns the command used to build/verify it.
+func detectBuildCommand(dir string) ([]string, bool) {
+	switch {
+	case fileExists(filepath.Join(dir, "Makefile")):
+		return []string{"make", "build"}, true
+	case fileExists(filepath.Join(dir, "go.mod")):
+		return []string{"go", "build", "./..."}, true
+	case fileExists(filepath.Join(dir, "package.json")):
+		return []string{"npm", "run", "build"}, true
+	case fileExists(filepath.Join(dir, "Dockerfile")):
+		return []string{"docker", "build", "."}, true
+	default:
+		return nil, false
+	}
+}
+
+func runBuildCommand(dir string, buildCmd []string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *flagBuildTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, buildCmd[0], buildCmd[1:]...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+var diagnosticLineRE = regexp.MustCompile(`(?m)^([^\s:][^:\n]*\.[a-zA-Z0-9]+):(\d+):(?:\d+:)?\s*(.+)$`)
+
+// parseDiagnostics extracts {file, line, message} records from build output
+// in the common "path/to/file.ext:line: message" format emitted by the Go
+// toolchain and most other compilers/linters.
+func parseDiagnostics(output string) []diagnostic {
+	var diagnostics []diagnostic
+	for _, m := range diagnosticLineRE.FindAllStringSubmatch(output, -1) {
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		diagnostics = append(diagnostics, diagnostic{File: m[1], Line: line, Message: m[3]})
+	}
+	return diagnostics
+}
+
+func groupDiagnosticsByFile(diagnostics []diagnostic) map[string][]diagnostic {
+	byFile := map[string][]diagnostic{}
+	for _, d := range diagnostics {
+		byFile[d.File] = append(byFile[d.File], d)
+	}
+	return byFile
+}
+
+// archiveFileBeforeRepair saves the pre-repair contents of fp under
+// .smol-dev/history/round-N/ so a user can inspect or revert a repair round.
+func archiveFileBeforeRepair(round int, relPath, fp string) error {
+	if !fileExists(fp) {
+		return nil
+	}
+	src, err := os.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+
+	dest := filepath.Join(pathInTargetDir(".smol-dev"), "history", fmt.Sprintf("round-%v", round), relPath)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, src, 0644)
+}
+
+func repairFile(provider Provider, prompt, fp, sharedDepsYaml string, filePaths []string, diagnostics []diagnostic) error {
+	current, err := readFile(fp)
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %w", fp, err)
+	}
+
+	spt := prompts.NewPromptTemplate(repairSystemPrompt, []string{"prompt", "filepaths_string", "shared_dependencies"})
+	pt := prompts.NewPromptTemplate(repairPrompt, []string{"filename", "current_contents", "diagnostics"})
+
+	inputs := map[string]interface{}{
+		"prompt":              prompt,
+		"filepaths_string":    filePaths,
+		"shared_dependencies": sharedDepsYaml,
+		"filename":            fp,
+		"current_contents":    current,
+		"diagnostics":         formatDiagnostics(diagnostics),
+	}
+
+	systemPrompt, err := spt.Format(inputs)
+	if err != nil {
+		return fmt.Errorf("failed to format system prompt: %w", err)
+	}
+
+	repairMsg, err := pt.Format(inputs)
+	if err != nil {
+		return fmt.Errorf("failed to format repair prompt: %w", err)
+	}
+
+	f, err := os.OpenFile(fp, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %v: %w", fp, err)
+	}
+	defer f.Close()
+
+	err = provider.GenerateStream(context.Background(), codegenModel(), systemPrompt, repairMsg, func(ctx context.Context, chunk []byte) error {
+		if _, err := f.Write(chunk); err != nil {
+			return fmt.Errorf("failed to write to file %v: %w", fp, err)
+		}
+		return f.Sync()
+	})
+	if err != nil {
+		return err
+	}
+
+	if !*flagNoFormat {
+		if err := formatGeneratedFile(fp); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to format %v: %v\n", fp, err)
+		}
+	}
+
+	return nil
+}
+
+func formatDiagnostics(diagnostics []diagnostic) string {
+	var s string
+	for _, d := range diagnostics {
+		s += fmt.Sprintf("line %v: %v\n", d.Line, d.Message)
+	}
+	return s
+}
+
+const repairSystemPrompt = `
+You are an AI developer who is trying to fix a program that failed to build.
+
+the app is: {{.prompt}}
+
+the files we have decided to generate are: {{ toJson .filepaths_string}}
+
+the shared dependencies (like filenames and variable names) we have decided on are: {{.shared_dependencies}}
+
+only write valid code for the given filepath and file type, and return only the code.
+do not add any other explanation, only return valid code for that file type.`
+
+const repairPrompt = `
+The file {{.filename}} failed to build. Here is its current content:
+
+---
+{{.current_contents}}
+---
+
+The build reported the following diagnostics for this file:
+
+---
+{{.diagnostics}}
+---
+
+Fix the file so it builds successfully while preserving its intended behavior.
+Do not include code fences in your response. Return only the corrected contents of {{.filename}}.
+`